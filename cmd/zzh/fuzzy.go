@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// fuzzyMatch scores how well query matches target using a Smith-Waterman
+// style local alignment: every matched character earns a point, runs of
+// consecutive matches compound that bonus, matches that start a word (after
+// '-', '_', '.' or a camelCase transition) earn an extra bonus, and gaps
+// between matched characters are penalized proportional to their length.
+// It returns the matched target indices (for highlighting) and whether
+// every rune in query was found in order.
+func fuzzyMatch(query, target string) (score int, matched []int, ok bool) {
+	if query == "" {
+		return 0, nil, true
+	}
+
+	lowerQuery := strings.ToLower(query)
+	lowerTarget := strings.ToLower(target)
+
+	qi := 0
+	consecutive := 0
+	lastMatch := -1
+
+	for ti := 0; ti < len(lowerTarget) && qi < len(lowerQuery); ti++ {
+		if lowerTarget[ti] != lowerQuery[qi] {
+			consecutive = 0
+			continue
+		}
+
+		bonus := 1
+		if consecutive > 0 {
+			bonus += consecutive * 3
+		}
+		if isWordBoundary(target, ti) {
+			bonus += 5
+		}
+		if lastMatch >= 0 {
+			gap := ti - lastMatch - 1
+			bonus -= gap
+		}
+
+		score += bonus
+		matched = append(matched, ti)
+		consecutive++
+		lastMatch = ti
+		qi++
+	}
+
+	return score, matched, qi == len(lowerQuery)
+}
+
+// isWordBoundary reports whether target[i] starts a new "word": it's the
+// first character, follows a separator, or is an uppercase letter following
+// a lowercase one (camelCase).
+func isWordBoundary(target string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	switch target[i-1] {
+	case '-', '_', '.', ' ', '/':
+		return true
+	}
+	prev, cur := rune(target[i-1]), rune(target[i])
+	return isLower(prev) && isUpper(cur)
+}
+
+func isUpper(r rune) bool { return r >= 'A' && r <= 'Z' }
+func isLower(r rune) bool { return r >= 'a' && r <= 'z' }
+
+// frecencyDecayPerDay controls how quickly a host's frecency bonus fades as
+// time passes since it was last used.
+const frecencyDecayPerDay = 0.05
+
+// frecencyBonus scores a host's usage history: frequent and recently used
+// hosts rank higher, with a deliberate decay so stale history fades out.
+func frecencyBonus(entry historyEntry, weight float64) float64 {
+	if entry.Count == 0 {
+		return 0
+	}
+	daysSinceUse := time.Since(time.Unix(entry.LastUsed, 0)).Hours() / 24
+	return weight * (math.Log1p(float64(entry.Count)) - frecencyDecayPerDay*daysSinceUse)
+}
+
+// sortItemsByFrecency orders items by frecency alone (highest first), so the
+// picker shows its best guesses even before the user has typed a filter
+// term — list.Model only calls its FilterFunc once there's a non-empty
+// query, so the initial, unfiltered order has to be sorted up front.
+func sortItemsByFrecency(items []list.Item, hist History, weight float64) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return frecencyBonus(hist[items[i].FilterValue()], weight) > frecencyBonus(hist[items[j].FilterValue()], weight)
+	})
+}
+
+// newFrecencyFilter builds a list.FilterFunc that ranks targets by fuzzy
+// match score combined with their frecency in hist, weighted by weight.
+func newFrecencyFilter(hist History, weight float64) list.FilterFunc {
+	return func(term string, targets []string) []list.Rank {
+		type scored struct {
+			rank  list.Rank
+			score float64
+		}
+
+		var candidates []scored
+		for i, target := range targets {
+			matchScore, matched, ok := fuzzyMatch(term, target)
+			if !ok {
+				continue
+			}
+			total := float64(matchScore) + frecencyBonus(hist[target], weight)
+			candidates = append(candidates, scored{
+				rank:  list.Rank{Index: i, MatchedIndexes: matched},
+				score: total,
+			})
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score > candidates[j].score
+		})
+
+		ranks := make([]list.Rank, len(candidates))
+		for i, c := range candidates {
+			ranks[i] = c.rank
+		}
+		return ranks
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// historyEntry tracks how often and how recently a host has been chosen,
+// for ranking it in the fuzzy picker.
+type historyEntry struct {
+	Count    int   `json:"count"`
+	LastUsed int64 `json:"lastUsed"`
+}
+
+// History is the on-disk frecency record, keyed by host alias.
+type History map[string]historyEntry
+
+// historyPath returns ~/.config/zzh/history.json.
+func historyPath() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	return filepath.Join(usr.HomeDir, ".config", "zzh", "history.json"), nil
+}
+
+// loadHistory reads the frecency history from disk, returning an empty
+// History if it doesn't exist yet.
+func loadHistory() (History, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return History{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	hist := History{}
+	if err := json.Unmarshal(data, &hist); err != nil {
+		return nil, fmt.Errorf("failed to parse history file: %w", err)
+	}
+	return hist, nil
+}
+
+// touch records that host was just chosen, bumping its count and last-used
+// timestamp, then saves the history back to disk.
+func (h History) touch(host string) error {
+	entry := h[host]
+	entry.Count++
+	entry.LastUsed = time.Now().Unix()
+	h[host] = entry
+
+	return h.save()
+}
+
+func (h History) save() error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
@@ -18,14 +18,22 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/kevinburke/ssh_config"
+	"github.com/mikemackintosh/zzh/internal/configresolver"
 )
 
 var (
 	// Command line flags
-	zzhMode    = flag.Bool("zzh", false, "Run in zzh panel mode")
-	zzhPanelID = flag.String("panel-id", "", "zzh panel ID (when running in zzh mode)")
-	zzhLogFile = flag.String("log-file", "", "Log file path (when running in zzh mode)")
+	zzhMode        = flag.Bool("zzh", false, "Run in zzh panel mode")
+	zzhPanelID     = flag.String("panel-id", "", "zzh panel ID (when running in zzh mode)")
+	zzhLogFile     = flag.String("log-file", "", "Log file path (when running in zzh mode)")
+	useEmbeddedSSH = flag.Bool("embedded", false, "Use the in-process SSH client instead of exec'ing the system ssh binary")
+	recordSession  = flag.Bool("record", false, "Record the session to disk")
+	recordFormat   = flag.String("record-format", "cast", "Recording format: cast or raw")
+	recordInput    = flag.Bool("record-input", false, "Also record keystrokes sent to the session (off by default since it can capture passwords)")
+	panelOpenHost  = flag.String("panel-open", "", "Open this host in the running panel identified by --panel-id")
+	panelClosePane = flag.Int("panel-close", 0, "Close the panel with the given ID in the running panel identified by --panel-id")
+	panelSendKeys  = flag.String("panel-send", "", "Send these literal keys to --panel-close's panel via --panel-id")
+	frecencyWeight = flag.Float64("frecency-weight", 1.0, "How strongly usage history influences host ranking in the picker")
 
 	// Version information
 	version = "0.1.0"
@@ -40,6 +48,8 @@ type SSHHost struct {
 	user         string
 	port         string
 	identityFile string
+	proxyJump    []configresolver.SSHHop
+	proxyCommand string
 }
 
 // Implement the list.Item interface for SSHHost
@@ -84,6 +94,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				host := m.list.SelectedItem().(SSHHost)
 				m.selectedHost = &host
 
+				if hist, err := loadHistory(); err == nil {
+					hist.touch(host.name)
+				}
+
 				// Set connecting flag
 				m.connecting = true
 
@@ -171,74 +185,61 @@ func listMargins() (top, right, bottom, left int) {
 	return 1, 2, 1, 2
 }
 
-// Load SSH hosts from config file
+// Load SSH hosts from config file, following Include directives, Match
+// blocks and the full wildcard cascade via configresolver.
 func loadSSHHosts() ([]list.Item, error) {
 	usr, err := user.Current()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current user: %w", err)
 	}
 
-	// Open SSH config file
 	configFile := filepath.Join(usr.HomeDir, ".ssh", "config")
-	f, err := os.Open(configFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open SSH config file: %w", err)
-	}
-	defer f.Close()
-
-	// Parse SSH config
-	cfg, err := ssh_config.Decode(f)
+	resolver, err := configresolver.NewResolver(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SSH config: %w", err)
+		return nil, fmt.Errorf("failed to load SSH config: %w", err)
 	}
 
-	// Extract hosts
 	items := []list.Item{}
-	for _, host := range cfg.Hosts {
-		for _, pattern := range host.Patterns {
-			// Skip wildcard/pattern hosts
-			if strings.Contains(pattern.String(), "*") {
-				continue
-			}
-
-			hostName := pattern.String()
-
-			hostname := ssh_config.Get(hostName, "HostName")
-			if hostname == "" {
-				hostname = hostName
-			}
+	for _, alias := range resolver.Aliases() {
+		resolved, err := resolver.Resolve(alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve host %s: %w", alias, err)
+		}
 
-			user := ssh_config.Get(hostName, "User")
-			if user == "" {
-				user = usr.Username
-			}
+		identityFile := resolved.IdentityFile
+		if identityFile == "" {
+			identityFile = filepath.Join(usr.HomeDir, ".ssh", "id_rsa")
+		}
 
-			port := ssh_config.Get(hostName, "Port")
-			if port == "" {
-				port = "22"
-			}
+		items = append(items, SSHHost{
+			name:         alias,
+			hostname:     resolved.HostName,
+			user:         resolved.User,
+			port:         resolved.Port,
+			identityFile: identityFile,
+			proxyJump:    resolved.ProxyJump,
+			proxyCommand: resolved.ProxyCommand,
+		})
+	}
 
-			// Get identity file
-			identityFile := ssh_config.Get(hostName, "IdentityFile")
-			if identityFile == "" {
-				// Default to id_rsa if not specified
-				identityFile = filepath.Join(usr.HomeDir, ".ssh", "id_rsa")
-			} else if strings.HasPrefix(identityFile, "~") {
-				// Expand ~ to home directory
-				identityFile = strings.Replace(identityFile, "~", usr.HomeDir, 1)
-			}
+	return items, nil
+}
 
-			items = append(items, SSHHost{
-				name:         hostName,
-				hostname:     hostname,
-				user:         user,
-				port:         port,
-				identityFile: identityFile,
-			})
+// formatProxyJumpFlag renders a resolved ProxyJump chain back into the
+// "user@host:port,user@host:port" form the ssh binary's -J flag expects.
+func formatProxyJumpFlag(hops []configresolver.SSHHop) string {
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		spec := hop.HostName
+		if hop.Port != "" && hop.Port != "22" {
+			spec = fmt.Sprintf("%s:%s", spec, hop.Port)
+		}
+		if hop.User != "" {
+			spec = fmt.Sprintf("%s@%s", hop.User, spec)
 		}
+		parts[i] = spec
 	}
-
-	return items, nil
+	return strings.Join(parts, ",")
 }
 
 // Connect to SSH host using the native SSH client
@@ -262,6 +263,23 @@ func connectToSSHNative(host SSHHost, inZzhPanel bool) error {
 	fmt.Fprintf(logFile, "=== SSH Session to %s started at %s ===\n\n",
 		host.name, time.Now().Format(time.RFC3339))
 
+	// Optionally record the session in asciicast v2 format, alongside the
+	// plain text log above.
+	var rec *Recorder
+	fd := int(os.Stdin.Fd())
+	if *recordSession && *recordFormat == "cast" {
+		width, height, err := getTerminalSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		recPath := strings.TrimSuffix(logFileName, filepath.Ext(logFileName)) + ".cast"
+		rec, err = NewRecorder(recPath, width, height, *recordInput)
+		if err != nil {
+			return fmt.Errorf("failed to start recording: %w", err)
+		}
+		defer rec.Close()
+	}
+
 	// Build the ssh command with arguments
 	sshArgs := []string{}
 
@@ -278,6 +296,11 @@ func connectToSSHNative(host SSHHost, inZzhPanel bool) error {
 		sshArgs = append(sshArgs, "-p", host.port)
 	}
 
+	// Chain through any ProxyJump hops
+	if len(host.proxyJump) > 0 {
+		sshArgs = append(sshArgs, "-J", formatProxyJumpFlag(host.proxyJump))
+	}
+
 	// Add host address
 	hostAddr := fmt.Sprintf("%s@%s", host.user, host.hostname)
 	sshArgs = append(sshArgs, hostAddr)
@@ -292,6 +315,11 @@ func connectToSSHNative(host SSHHost, inZzhPanel bool) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
 	cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
+	if rec != nil {
+		cmd.Stdin = io.TeeReader(os.Stdin, inputRecordingWriter{rec})
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, outputRecordingWriter{rec})
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, outputRecordingWriter{rec})
+	}
 
 	// Start the SSH command
 	err = cmd.Start()
@@ -299,84 +327,15 @@ func connectToSSHNative(host SSHHost, inZzhPanel bool) error {
 		return fmt.Errorf("failed to start SSH: %w", err)
 	}
 
-	// Set up signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-
-	// Wait for command completion or signal
-	go func() {
-		for sig := range sigChan {
-			// Forward signals to SSH process
-			if cmd.Process != nil {
-				cmd.Process.Signal(sig)
+	if rec != nil {
+		stopResize := make(chan struct{})
+		defer close(stopResize)
+		watchWindowResize(fd, stopResize, func() {
+			w, h, err := getTerminalSize(fd)
+			if err == nil {
+				rec.Resize(w, h)
 			}
-		}
-	}()
-
-	// Wait for SSH to complete
-	err = cmd.Wait()
-
-	// Stop signal handling
-	signal.Stop(sigChan)
-	close(sigChan)
-
-	// Log session end
-	fmt.Fprintf(logFile, "=== SSH Session ended at %s ===\n",
-		time.Now().Format(time.RFC3339))
-
-	if err != nil && cmd.ProcessState.ExitCode() != 0 {
-		return fmt.Errorf("SSH exited with code %d: %w",
-			cmd.ProcessState.ExitCode(), err)
-	}
-
-	return nil
-}
-
-// Connect to SSH host via zzh panel command
-func connectToSSHViaZzh(host SSHHost) error {
-	// Create a timestamp for the log file
-	timestamp := time.Now().Format("20060102-150405")
-	logFileName := fmt.Sprintf("ssh_session_%s_%s.log", host.name, timestamp)
-	if *zzhLogFile != "" {
-		logFileName = *zzhLogFile
-	}
-
-	logFile, err := os.Create(logFileName)
-	if err != nil {
-		return fmt.Errorf("failed to create log file: %w", err)
-	}
-	defer logFile.Close()
-
-	// Log session start
-	fmt.Fprintf(logFile, "=== SSH Session to %s via zzh panel started at %s ===\n\n",
-		host.name, time.Now().Format(time.RFC3339))
-
-	// Build the zzh command with arguments to connect to the host
-	zzhArgs := []string{"connect"}
-
-	// Add host name
-	zzhArgs = append(zzhArgs, host.name)
-
-	// If panel ID is specified, add it
-	if *zzhPanelID != "" {
-		zzhArgs = append(zzhArgs, "--panel-id", *zzhPanelID)
-	}
-
-	// Create the zzh command
-	cmd := exec.Command("zzh", zzhArgs...)
-
-	// Set environment variables
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
-
-	// Set up I/O
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
-	cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
-
-	// Start the zzh command
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Errorf("failed to start zzh connect: %w", err)
+		})
 	}
 
 	// Set up signal handling
@@ -386,14 +345,14 @@ func connectToSSHViaZzh(host SSHHost) error {
 	// Wait for command completion or signal
 	go func() {
 		for sig := range sigChan {
-			// Forward signals to zzh process
+			// Forward signals to SSH process
 			if cmd.Process != nil {
 				cmd.Process.Signal(sig)
 			}
 		}
 	}()
 
-	// Wait for zzh to complete
+	// Wait for SSH to complete
 	err = cmd.Wait()
 
 	// Stop signal handling
@@ -405,7 +364,7 @@ func connectToSSHViaZzh(host SSHHost) error {
 		time.Now().Format(time.RFC3339))
 
 	if err != nil && cmd.ProcessState.ExitCode() != 0 {
-		return fmt.Errorf("zzh exited with code %d: %w",
+		return fmt.Errorf("SSH exited with code %d: %w",
 			cmd.ProcessState.ExitCode(), err)
 	}
 
@@ -431,9 +390,41 @@ func setupLogging() (*os.File, error) {
 }
 
 func main() {
+	// Handle subcommands that don't participate in the picker UI.
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Parse command line flags
 	flag.Parse()
 
+	// External tools can address a running panel over its control socket
+	// instead of launching the UI.
+	if *zzhPanelID != "" && (*panelOpenHost != "" || *panelClosePane != 0 || *panelSendKeys != "") {
+		if err := sendPanelControlCommand(*zzhPanelID, panelControlCommand{
+			Action:  panelControlAction(),
+			Host:    *panelOpenHost,
+			PanelID: *panelClosePane,
+			Data:    *panelSendKeys,
+		}); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *zzhMode {
+		if err := runPanelMode(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Set up logging
 	logFile, err := setupLogging()
 	if err != nil {
@@ -472,11 +463,23 @@ func main() {
 	delegate.Styles.NormalDesc = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("246"))
 
+	// Rank hosts by fuzzy match score combined with recency/frequency of use
+	history, err := loadHistory()
+	if err != nil {
+		fmt.Printf("Warning: failed to load host history: %v\n", err)
+		history = History{}
+	}
+
+	// Sort the default (unfiltered) order by frecency too, since list.Model
+	// only consults l.Filter once the user has typed a query.
+	sortItemsByFrecency(items, history, *frecencyWeight)
+
 	// Set up list with sensible defaults
 	l := list.New(items, delegate, 80, 20)
 	l.Title = fmt.Sprintf("SSH Hosts - %s", version)
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
+	l.Filter = newFrecencyFilter(history, *frecencyWeight)
 	l.Styles.Title = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("39")).
 		Background(lipgloss.Color("236")).
@@ -513,10 +516,11 @@ func main() {
 
 			// Determine whether to use native SSH or zzh panel integration
 			var connectErr error
-			if *zzhMode {
-				// Use zzh to connect
-				connectErr = connectToSSHViaZzh(host)
-			} else {
+			switch {
+			case *useEmbeddedSSH:
+				// Use the in-process SSH client
+				connectErr = connectToSSHEmbedded(host, false)
+			default:
 				// Use native SSH
 				connectErr = connectToSSHNative(host, false)
 			}
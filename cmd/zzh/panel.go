@@ -0,0 +1,735 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/crypto/ssh"
+)
+
+// ansiState tracks where Filter is within an escape sequence that may span
+// multiple calls to Filter (a remote program's output can be chunked
+// mid-sequence across PTY reads).
+type ansiState int
+
+const (
+	ansiGround  ansiState = iota // not in an escape sequence
+	ansiEscape                   // just consumed ESC
+	ansiCSI                      // inside ESC [ ... (Control Sequence Introducer)
+	ansiOSC                      // inside ESC ] ... (Operating System Command)
+)
+
+// ansiStripper removes ANSI/VT escape sequences (cursor movement, SGR
+// colors, OSC title-setting, etc.) from a byte stream, so that a program
+// like vim or htop that repaints the screen with cursor positioning codes
+// doesn't leak raw escape bytes into the scrollback and corrupt the
+// lipgloss-rendered pane. It is stateful so a sequence split across two
+// Write calls is still recognized and dropped in full.
+type ansiStripper struct {
+	state ansiState
+}
+
+// Filter returns p with any ANSI/VT escape sequences removed.
+func (a *ansiStripper) Filter(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, c := range p {
+		switch a.state {
+		case ansiGround:
+			if c == 0x1b {
+				a.state = ansiEscape
+				continue
+			}
+			out = append(out, c)
+
+		case ansiEscape:
+			switch c {
+			case '[':
+				a.state = ansiCSI
+			case ']':
+				a.state = ansiOSC
+			default:
+				// A two-byte escape (ESC M, ESC c, ...); already consumed.
+				a.state = ansiGround
+			}
+
+		case ansiCSI:
+			// CSI sequences end at their first "final byte", 0x40-0x7e.
+			if c >= 0x40 && c <= 0x7e {
+				a.state = ansiGround
+			}
+
+		case ansiOSC:
+			// OSC sequences end at BEL, or at ESC \ (the ST terminator);
+			// re-entering ansiEscape lets the next byte close it out.
+			switch c {
+			case 0x07:
+				a.state = ansiGround
+			case 0x1b:
+				a.state = ansiEscape
+			}
+		}
+	}
+	return out
+}
+
+// scrollbackBuffer is a bounded ring of a session's recent output, used to
+// render a panel's viewport and to keep unfocused panels' history around
+// when they come back into focus.
+type scrollbackBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	pending string
+	max     int
+	ansi    ansiStripper
+}
+
+func newScrollbackBuffer(max int) *scrollbackBuffer {
+	return &scrollbackBuffer{max: max}
+}
+
+// Write appends p to the buffer, splitting completed lines off of it. It
+// satisfies io.Writer so it can sit directly in a session's Stdout chain.
+func (b *scrollbackBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending += string(b.ansi.Filter(p))
+	for {
+		idx := strings.IndexByte(b.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		b.lines = append(b.lines, strings.TrimRight(b.pending[:idx], "\r"))
+		b.pending = b.pending[idx+1:]
+	}
+	if len(b.lines) > b.max {
+		b.lines = b.lines[len(b.lines)-b.max:]
+	}
+	return len(p), nil
+}
+
+// Tail returns the last n rendered lines, including any not-yet-terminated
+// partial line.
+func (b *scrollbackBuffer) Tail(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := append([]string{}, b.lines...)
+	if b.pending != "" {
+		lines = append(lines, b.pending)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// SessionPanel is a single PTY-backed SSH session hosted inside a zzh panel
+// layout.
+type SessionPanel struct {
+	id          int
+	host        SSHHost
+	client      *ssh.Client
+	jumpClients []*ssh.Client
+	session     *ssh.Session
+	stdin       io.WriteCloser
+	input       chan []byte
+	scrollback  *scrollbackBuffer
+	width       int
+	height      int
+	closed      bool
+	err         error
+}
+
+// newSessionPanel dials host and opens a PTY shell on it, draining remote
+// output into a scrollback buffer. prog is used to wake the Bubble Tea
+// event loop whenever new output arrives so the focused panel redraws.
+func newSessionPanel(id int, host SSHHost, width, height int, prog *tea.Program) (*SessionPanel, error) {
+	authMethods, err := sshAuthMethods(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SSH auth methods: %w", err)
+	}
+	hostKeyCallback, err := sshHostKeyCallback()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up host key verification: %w", err)
+	}
+
+	addr := fmt.Sprintf("%s:%s", host.hostname, host.port)
+	client, jumpClients, err := dialThroughHops(host.proxyJump, addr, &ssh.ClientConfig{
+		User:            host.user,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", host.name, err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		closeClientChain(client, jumpClients)
+		return nil, fmt.Errorf("failed to open session on %s: %w", host.name, err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
+		session.Close()
+		closeClientChain(client, jumpClients)
+		return nil, fmt.Errorf("failed to request PTY on %s: %w", host.name, err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		closeClientChain(client, jumpClients)
+		return nil, fmt.Errorf("failed to open stdin pipe on %s: %w", host.name, err)
+	}
+
+	panel := &SessionPanel{
+		id:          id,
+		host:        host,
+		client:      client,
+		jumpClients: jumpClients,
+		session:     session,
+		stdin:       stdin,
+		input:       make(chan []byte, 256),
+		scrollback:  newScrollbackBuffer(2000),
+		width:       width,
+		height:      height,
+	}
+	go panel.drainInput()
+
+	remoteOut, err := session.StdoutPipe()
+	if err != nil {
+		close(panel.input)
+		session.Close()
+		closeClientChain(client, jumpClients)
+		return nil, fmt.Errorf("failed to open stdout pipe on %s: %w", host.name, err)
+	}
+
+	if err := session.Shell(); err != nil {
+		close(panel.input)
+		session.Close()
+		closeClientChain(client, jumpClients)
+		return nil, fmt.Errorf("failed to start shell on %s: %w", host.name, err)
+	}
+
+	// Background drain: unfocused panels keep receiving output so their
+	// scrollback stays current even while another panel has focus.
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, readErr := remoteOut.Read(buf)
+			if n > 0 {
+				panel.scrollback.Write(buf[:n])
+				if prog != nil {
+					prog.Send(panelOutputMsg{panelID: panel.id})
+				}
+			}
+			if readErr != nil {
+				panel.closed = true
+				if readErr != io.EOF {
+					panel.err = readErr
+				}
+				if prog != nil {
+					prog.Send(panelOutputMsg{panelID: panel.id})
+				}
+				return
+			}
+		}
+	}()
+
+	return panel, nil
+}
+
+// Resize requests a window-change on the remote PTY.
+func (p *SessionPanel) Resize(width, height int) {
+	if p.closed || width <= 0 || height <= 0 {
+		return
+	}
+	p.width, p.height = width, height
+	p.session.WindowChange(height, width)
+}
+
+// Send queues raw keystrokes to be written to the remote shell. Writing
+// happens on the panel's own drainInput goroutine rather than here, so a
+// remote program that stops draining its stdin (a paused job, less, a hung
+// process) blocks only that panel instead of Bubble Tea's single shared
+// Update goroutine that every other pane also depends on.
+func (p *SessionPanel) Send(data []byte) {
+	if p.closed {
+		return
+	}
+	select {
+	case p.input <- data:
+	default:
+		// The remote shell's stdin has been stuck long enough to fill the
+		// backlog; drop rather than block the caller.
+	}
+}
+
+// drainInput writes queued keystrokes to the remote shell's stdin pipe,
+// one panel's worth per goroutine, so a blocking write never stalls the
+// rest of the panel program. It exits once input is closed in Close.
+func (p *SessionPanel) drainInput() {
+	for data := range p.input {
+		p.stdin.Write(data)
+	}
+}
+
+// Close tears down the panel's SSH session and connection.
+func (p *SessionPanel) Close() {
+	p.closed = true
+	close(p.input)
+	p.session.Close()
+	closeClientChain(p.client, p.jumpClients)
+}
+
+// panelOutputMsg notifies the root model that a panel received new output.
+type panelOutputMsg struct {
+	panelID int
+}
+
+// PanelModel is the Bubble Tea root model for `zzh --zzh` mode. It owns a
+// set of SessionPanels laid out in a binary split tree, similar to tmux.
+type PanelModel struct {
+	prog       *tea.Program
+	panels     []*SessionPanel
+	layout     *paneNode
+	focused    int
+	nextID     int
+	width      int
+	height     int
+	pendingCtl bool // true once ctrl+b has been pressed, awaiting the next key
+	picker     *pickerOverlayModel
+	controlSock string
+}
+
+// NewPanelModel creates an empty panel manager. Its control socket is opened
+// separately (runPanelMode calls listenPanelControl), and it is sized by the
+// first tea.WindowSizeMsg; panels are added via the host picker or the
+// control protocol.
+func NewPanelModel(controlSock string) *PanelModel {
+	return &PanelModel{
+		nextID:      1,
+		controlSock: controlSock,
+	}
+}
+
+func (m *PanelModel) Init() tea.Cmd {
+	return nil
+}
+
+// splitType describes how a paneNode's two children are arranged.
+type splitType int
+
+const (
+	splitNone splitType = iota
+	splitVertical       // side by side, ctrl+b %
+	splitHorizontal     // stacked, ctrl+b "
+)
+
+// paneNode is a node in the panel layout's binary split tree. Leaf nodes
+// hold a panel ID and have no children; interior nodes describe how their
+// two children share the available space. Leafness is determined by
+// first/second being nil, not by split's zero value — splitNone is a real
+// "no direction chosen yet" state for a not-yet-split interior node, and
+// conflating the two made a freshly-split pane with an unset split
+// direction render as if it were still a leaf.
+type paneNode struct {
+	panelID int
+	split   splitType
+	first   *paneNode
+	second  *paneNode
+	ratio   float64
+}
+
+// isLeaf reports whether n holds a panel directly rather than two children.
+func (n *paneNode) isLeaf() bool {
+	return n.first == nil && n.second == nil
+}
+
+func (m *PanelModel) panelByID(id int) *SessionPanel {
+	for _, p := range m.panels {
+		if p.id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// addPanel inserts a new panel into the layout, splitting the currently
+// focused pane according to dir. dir must be splitVertical or
+// splitHorizontal; splitNone is not a valid split direction and is
+// normalized to splitVertical so a caller that forgets to set one doesn't
+// silently corrupt the layout.
+func (m *PanelModel) addPanel(panel *SessionPanel, dir splitType) {
+	m.panels = append(m.panels, panel)
+
+	if dir == splitNone {
+		dir = splitVertical
+	}
+
+	if m.layout == nil {
+		m.layout = &paneNode{panelID: panel.id}
+		m.focused = panel.id
+		return
+	}
+
+	target := findLeaf(m.layout, m.focused)
+	if target == nil {
+		target = m.layout
+	}
+
+	existingID := target.panelID
+	target.panelID = 0
+	target.split = dir
+	target.ratio = 0.5
+	target.first = &paneNode{panelID: existingID}
+	target.second = &paneNode{panelID: panel.id}
+
+	m.focused = panel.id
+}
+
+// findLeaf locates the leaf node for panelID within the tree rooted at n.
+func findLeaf(n *paneNode, panelID int) *paneNode {
+	if n == nil {
+		return nil
+	}
+	if n.isLeaf() {
+		if n.panelID == panelID {
+			return n
+		}
+		return nil
+	}
+	if found := findLeaf(n.first, panelID); found != nil {
+		return found
+	}
+	return findLeaf(n.second, panelID)
+}
+
+// resizeLayout recomputes every panel's size from the current terminal
+// dimensions and pushes a window-change to each one.
+func (m *PanelModel) resizeLayout() {
+	if m.layout == nil {
+		return
+	}
+	m.applySize(m.layout, m.width, m.height)
+}
+
+func (m *PanelModel) applySize(n *paneNode, width, height int) {
+	if n == nil {
+		return
+	}
+	if n.isLeaf() {
+		if panel := m.panelByID(n.panelID); panel != nil {
+			panel.Resize(width, height)
+		}
+		return
+	}
+
+	switch n.split {
+	case splitHorizontal:
+		firstHeight := int(float64(height) * n.ratio)
+		m.applySize(n.first, width, firstHeight)
+		m.applySize(n.second, width, height-firstHeight)
+	default: // splitVertical, and splitNone treated the same way
+		firstWidth := int(float64(width) * n.ratio)
+		m.applySize(n.first, firstWidth, height)
+		m.applySize(n.second, width-firstWidth, height)
+	}
+}
+
+func (m *PanelModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.picker != nil {
+		return m.updatePicker(msg)
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resizeLayout()
+
+	case panelOutputMsg:
+		// Just a redraw trigger; View() reads scrollback directly.
+
+	case panelControlMsg:
+		m.handleControlMsg(msg.cmd)
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	return m, nil
+}
+
+// handleControlMsg applies a command received over the panel's unix control
+// socket (see panel_control.go).
+func (m *PanelModel) handleControlMsg(cmd panelControlCommand) {
+	switch cmd.Action {
+	case "open":
+		items, err := loadSSHHosts()
+		if err != nil {
+			return
+		}
+		for _, item := range items {
+			host := item.(SSHHost)
+			if host.name == cmd.Host {
+				id := m.nextID
+				m.nextID++
+				panel, err := newSessionPanel(id, host, m.width, m.height, m.prog)
+				if err == nil {
+					m.addPanel(panel, splitVertical)
+					m.resizeLayout()
+				}
+				return
+			}
+		}
+
+	case "close":
+		if panel := m.panelByID(cmd.PanelID); panel != nil {
+			panel.Close()
+		}
+
+	case "send":
+		if panel := m.panelByID(cmd.PanelID); panel != nil {
+			panel.Send([]byte(cmd.Data))
+		}
+	}
+}
+
+func (m *PanelModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pendingCtl {
+		m.pendingCtl = false
+		return m.handleControlKey(msg)
+	}
+
+	if msg.String() == "ctrl+b" {
+		m.pendingCtl = true
+		return m, nil
+	}
+
+	if panel := m.panelByID(m.focused); panel != nil {
+		if data := keyBytes(msg); len(data) > 0 {
+			panel.Send(data)
+		}
+	}
+	return m, nil
+}
+
+// keyBytes translates a parsed Bubble Tea key event into the literal byte
+// sequence a terminal would have sent for it. KeyMsg.String() is bubbletea's
+// human-readable name (KeyEnter.String() is the text "enter", not "\r"), so
+// forwarding it directly to the remote shell sends garbage for anything but
+// plain printable runes — Enter, Ctrl+C, Backspace, and the arrow keys would
+// never work.
+func keyBytes(msg tea.KeyMsg) []byte {
+	switch msg.Type {
+	case tea.KeyRunes:
+		return []byte(string(msg.Runes))
+	case tea.KeySpace:
+		return []byte(" ")
+	case tea.KeyUp:
+		return []byte("\x1b[A")
+	case tea.KeyDown:
+		return []byte("\x1b[B")
+	case tea.KeyRight:
+		return []byte("\x1b[C")
+	case tea.KeyLeft:
+		return []byte("\x1b[D")
+	case tea.KeyHome:
+		return []byte("\x1b[H")
+	case tea.KeyEnd:
+		return []byte("\x1b[F")
+	case tea.KeyPgUp:
+		return []byte("\x1b[5~")
+	case tea.KeyPgDown:
+		return []byte("\x1b[6~")
+	case tea.KeyDelete:
+		return []byte("\x1b[3~")
+	case tea.KeyInsert:
+		return []byte("\x1b[2~")
+	case tea.KeyShiftTab:
+		return []byte("\x1b[Z")
+	}
+
+	// Every other KeyType — Enter, Tab, Esc, Backspace, and every ctrl+<letter>
+	// combination — is defined in bubbletea as its literal control-code byte
+	// value (KeyEnter == '\r', KeyCtrlC == 0x03, KeyBackspace == 0x7f, ...),
+	// so it can be sent as-is.
+	if msg.Type >= 0 && msg.Type <= 127 {
+		return []byte{byte(msg.Type)}
+	}
+
+	return nil
+}
+
+// handleControlKey dispatches the key following ctrl+b.
+func (m *PanelModel) handleControlKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "n":
+		m.picker = newPickerOverlayModel()
+		m.picker.splitDir = splitVertical
+		return m, m.picker.Init()
+
+	case "%":
+		return m, m.splitCmd(splitVertical)
+
+	case "\"":
+		return m, m.splitCmd(splitHorizontal)
+
+	case "d":
+		// This quits the panel program and closes every session below it
+		// (see runPanelMode) — there is no daemonized process for sessions
+		// to persist in, so it is not a real tmux-style detach. Key kept
+		// as "d" for muscle-memory familiarity; see the quit message below.
+		return m, tea.Quit
+
+	default:
+		if n, err := strconv.Atoi(msg.String()); err == nil {
+			if n >= 1 && n <= len(m.panels) {
+				m.focused = m.panels[n-1].id
+			}
+		}
+	}
+	return m, nil
+}
+
+// splitCmd opens the host picker and, once a host is chosen, dials it and
+// splits the focused panel in direction dir.
+func (m *PanelModel) splitCmd(dir splitType) tea.Cmd {
+	m.picker = newPickerOverlayModel()
+	m.picker.splitDir = dir
+	return m.picker.Init()
+}
+
+// updatePicker routes messages to the host picker overlay while it is
+// shown, and handles the host being chosen.
+func (m *PanelModel) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd, chosen := m.picker.update(msg)
+	m.picker = updated
+
+	if chosen == nil {
+		return m, cmd
+	}
+
+	id := m.nextID
+	m.nextID++
+
+	panelWidth, panelHeight := m.width, m.height
+	panel, err := newSessionPanel(id, *chosen, panelWidth, panelHeight, m.prog)
+	dir := m.picker.splitDir
+	m.picker = nil
+	if err != nil {
+		return m, nil
+	}
+
+	m.addPanel(panel, dir)
+	m.resizeLayout()
+	return m, cmd
+}
+
+func (m *PanelModel) View() string {
+	if m.picker != nil {
+		return m.picker.view()
+	}
+
+	if m.layout == nil {
+		return "ctrl+b n to open a host, ctrl+b d to quit and close all sessions"
+	}
+
+	return m.renderNode(m.layout, m.width, m.height)
+}
+
+func (m *PanelModel) renderNode(n *paneNode, width, height int) string {
+	if n.isLeaf() {
+		return m.renderPanel(n.panelID, width, height)
+	}
+
+	switch n.split {
+	case splitVertical:
+		firstWidth := int(float64(width) * n.ratio)
+		left := m.renderNode(n.first, firstWidth, height)
+		right := m.renderNode(n.second, width-firstWidth, height)
+		return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+	default:
+		firstHeight := int(float64(height) * n.ratio)
+		top := m.renderNode(n.first, width, firstHeight)
+		bottom := m.renderNode(n.second, width, height-firstHeight)
+		return lipgloss.JoinVertical(lipgloss.Left, top, bottom)
+	}
+}
+
+// runPanelMode launches the multi-session panel manager: a Bubble Tea root
+// model that owns N PTY-backed SSH sessions, addressable over a unix
+// control socket named after --panel-id.
+func runPanelMode() error {
+	panelID := *zzhPanelID
+	if panelID == "" {
+		id, err := newPanelID()
+		if err != nil {
+			return err
+		}
+		panelID = id
+	}
+
+	model := NewPanelModel(panelID)
+	prog := tea.NewProgram(model, tea.WithAltScreen())
+	model.prog = prog
+
+	ln, err := listenPanelControl(panelID, prog)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	defer os.Remove(panelSocketPath(panelID))
+
+	fmt.Printf("zzh panel id: %s\n", panelID)
+
+	if _, err := prog.Run(); err != nil {
+		return fmt.Errorf("panel program exited with error: %w", err)
+	}
+
+	for _, panel := range model.panels {
+		panel.Close()
+	}
+
+	return nil
+}
+
+func (m *PanelModel) renderPanel(panelID int, width, height int) string {
+	panel := m.panelByID(panelID)
+	borderColor := lipgloss.Color("240")
+	if panelID == m.focused {
+		borderColor = lipgloss.Color("170")
+	}
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(borderColor).
+		Width(width - 2).
+		Height(height - 2)
+
+	if panel == nil {
+		return style.Render("")
+	}
+
+	lines := panel.scrollback.Tail(height - 2)
+	body := strings.Join(lines, "\n")
+	if panel.err != nil {
+		body = fmt.Sprintf("[%s closed: %v]", panel.host.name, panel.err)
+	}
+
+	return style.Render(body)
+}
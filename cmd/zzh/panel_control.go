@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panelControlCommand is a single instruction sent over a panel's control
+// socket, one JSON object per line.
+type panelControlCommand struct {
+	Action  string `json:"action"` // "open", "close", or "send"
+	Host    string `json:"host,omitempty"`
+	PanelID int    `json:"panel_id,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// panelControlMsg wraps a received control command as a Bubble Tea message
+// so it can be processed on the model's own goroutine.
+type panelControlMsg struct {
+	cmd panelControlCommand
+}
+
+// panelSocketPath returns the unix socket path for a given --panel-id.
+func panelSocketPath(panelID string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("zzh-panel-%s.sock", panelID))
+}
+
+// newPanelID generates a short random identifier for an unnamed panel.
+func newPanelID() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate panel id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// listenPanelControl opens the control socket for panelID and forwards every
+// decoded command to prog as a panelControlMsg. It returns the listener so
+// the caller can close it on shutdown.
+func listenPanelControl(panelID string, prog *tea.Program) (net.Listener, error) {
+	sockPath := panelSocketPath(panelID)
+	os.Remove(sockPath) // drop a stale socket from a previous crash
+
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on panel control socket: %w", err)
+	}
+	// Control commands can open/close/send keystrokes to this panel's live
+	// SSH sessions, so restrict the socket to its owner — otherwise any
+	// local user who guesses the panel id could inject input on a shared
+	// host.
+	if err := os.Chmod(sockPath, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict panel control socket permissions: %w", err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handlePanelControlConn(conn, prog)
+		}
+	}()
+
+	return ln, nil
+}
+
+func handlePanelControlConn(conn net.Conn, prog *tea.Program) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var cmd panelControlCommand
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			continue
+		}
+		prog.Send(panelControlMsg{cmd: cmd})
+		fmt.Fprintln(conn, "ok")
+	}
+}
+
+// panelControlAction determines which control command the --panel-open,
+// --panel-close and --panel-send flags describe.
+func panelControlAction() string {
+	switch {
+	case *panelOpenHost != "":
+		return "open"
+	case *panelClosePane != 0 && *panelSendKeys == "":
+		return "close"
+	default:
+		return "send"
+	}
+}
+
+// sendPanelControlCommand dials panelID's control socket and delivers a
+// single command, for use by external tools (or a second zzh invocation).
+func sendPanelControlCommand(panelID string, cmd panelControlCommand) error {
+	conn, err := net.Dial("unix", panelSocketPath(panelID))
+	if err != nil {
+		return fmt.Errorf("failed to reach panel %s: %w", panelID, err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	if err := enc.Encode(cmd); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read reply: %w", err)
+	}
+	if reply != "ok\n" {
+		return fmt.Errorf("panel rejected command: %s", reply)
+	}
+	return nil
+}
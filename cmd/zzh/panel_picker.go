@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pickerOverlayModel is the host list shown over a PanelModel when opening
+// a new panel (ctrl+b n) or splitting an existing one (ctrl+b % / "). It
+// returns the chosen host to whichever pane requested it.
+type pickerOverlayModel struct {
+	list     list.Model
+	splitDir splitType
+	err      error
+}
+
+func newPickerOverlayModel() *pickerOverlayModel {
+	items, err := loadSSHHosts()
+
+	delegate := list.NewDefaultDelegate()
+	l := list.New(items, delegate, 60, 20)
+	l.Title = "Open host in panel"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(true)
+	l.Styles.Title = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("39")).
+		Background(lipgloss.Color("236")).
+		Padding(0, 1).
+		Bold(true)
+
+	return &pickerOverlayModel{list: l, err: err}
+}
+
+func (p *pickerOverlayModel) Init() tea.Cmd {
+	return nil
+}
+
+// update handles a message while the overlay is shown. It returns the
+// (possibly new) overlay model, a command to run, and a non-nil host once
+// the user has made a selection.
+func (p *pickerOverlayModel) update(msg tea.Msg) (*pickerOverlayModel, tea.Cmd, *SSHHost) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return nil, nil, nil
+		case "enter":
+			if item := p.list.SelectedItem(); item != nil {
+				host := item.(SSHHost)
+				return p, nil, &host
+			}
+		}
+
+	case tea.WindowSizeMsg:
+		top, right, bottom, left := listMargins()
+		p.list.SetSize(msg.Width-left-right, msg.Height-top-bottom)
+	}
+
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd, nil
+}
+
+func (p *pickerOverlayModel) view() string {
+	if p.err != nil {
+		return fmt.Sprintf("failed to load hosts: %v", p.err)
+	}
+	return p.list.View()
+}
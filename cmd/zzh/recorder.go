@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// asciicastHeader is the first line of an asciinema v2 cast file.
+// See https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env"`
+}
+
+// Recorder writes an SSH session to disk in asciinema v2 format, so captures
+// can be replayed with `zzh replay` or any asciinema-compatible player.
+type Recorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	enc   *json.Encoder
+	start time.Time
+	recIn bool
+}
+
+// NewRecorder creates an asciicast v2 recording at path for a session of the
+// given initial terminal dimensions. If recordInput is true, keystrokes are
+// captured as "i" events in addition to the remote's "o" output events.
+func NewRecorder(path string, width, height int, recordInput bool) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	r := &Recorder{
+		file:  f,
+		enc:   json.NewEncoder(f),
+		start: time.Now(),
+		recIn: recordInput,
+	}
+
+	header := asciicastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Env: map[string]string{
+			"TERM":  "xterm-256color",
+			"SHELL": os.Getenv("SHELL"),
+		},
+	}
+	if err := r.enc.Encode(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write recording header: %w", err)
+	}
+
+	return r, nil
+}
+
+// writeEvent appends a single [elapsed, kind, data] frame to the recording.
+func (r *Recorder) writeEvent(kind, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := time.Since(r.start).Seconds()
+	return r.enc.Encode([]interface{}{elapsed, kind, data})
+}
+
+// Output records a chunk of remote output as an "o" event.
+func (r *Recorder) Output(p []byte) error {
+	return r.writeEvent("o", string(p))
+}
+
+// Input records a chunk of local keystrokes as an "i" event, if input
+// recording is enabled.
+func (r *Recorder) Input(p []byte) error {
+	if !r.recIn {
+		return nil
+	}
+	return r.writeEvent("i", string(p))
+}
+
+// Resize records a terminal resize as an "r" event.
+func (r *Recorder) Resize(cols, rows int) error {
+	return r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes and closes the underlying recording file.
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// outputRecordingWriter is an io.Writer adapter that feeds every chunk
+// written through it to a Recorder as "o" events, without otherwise
+// consuming the bytes - it is meant to be used alongside io.MultiWriter.
+type outputRecordingWriter struct {
+	rec *Recorder
+}
+
+func (w outputRecordingWriter) Write(p []byte) (int, error) {
+	if err := w.rec.Output(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// inputRecordingWriter is the "i" event equivalent of outputRecordingWriter.
+type inputRecordingWriter struct {
+	rec *Recorder
+}
+
+func (w inputRecordingWriter) Write(p []byte) (int, error) {
+	if err := w.rec.Input(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runReplay implements `zzh replay <file>`: it reads an asciicast v2
+// recording and writes its "o" events to stdout, pacing them using the
+// recorded time deltas so the session plays back as it happened.
+func runReplay(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: zzh replay <file>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		return fmt.Errorf("recording is empty")
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("failed to parse recording header: %w", err)
+	}
+
+	var lastElapsed float64
+	for scanner.Scan() {
+		var frame []json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil || len(frame) != 3 {
+			continue
+		}
+
+		var elapsed float64
+		var kind, data string
+		if err := json.Unmarshal(frame[0], &elapsed); err != nil {
+			continue
+		}
+		if err := json.Unmarshal(frame[1], &kind); err != nil {
+			continue
+		}
+		if err := json.Unmarshal(frame[2], &data); err != nil {
+			continue
+		}
+
+		if delta := elapsed - lastElapsed; delta > 0 {
+			time.Sleep(time.Duration(delta * float64(time.Second)))
+		}
+		lastElapsed = elapsed
+
+		if kind == "o" {
+			fmt.Print(data)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// fileEntry is one row in a browser pane's list, for either the local or
+// the remote filesystem.
+type fileEntry struct {
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+func (f fileEntry) Title() string {
+	if f.isDir {
+		return f.name + "/"
+	}
+	return f.name
+}
+
+func (f fileEntry) Description() string {
+	if f.isDir {
+		return fmt.Sprintf("%s", f.modTime.Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%d bytes  %s", f.size, f.modTime.Format("2006-01-02 15:04"))
+}
+
+func (f fileEntry) FilterValue() string { return f.name }
+
+// browserPane is one side of the two-pane SFTP browser: a directory listing
+// plus the set of entries the user has multi-selected with space.
+type browserPane struct {
+	list     list.Model
+	path     string
+	selected map[string]bool
+}
+
+// sftpBrowserModel is the Bubble Tea model shown when a session switches
+// into SFTP mode with ctrl+f: a local filesystem pane on the left, a remote
+// one (backed by the same *ssh.Client as the shell) on the right.
+type sftpBrowserModel struct {
+	sftpClient *sftp.Client
+	local      browserPane
+	remote     browserPane
+	focusRight bool
+
+	width, height int
+	status        string
+	err           error
+
+	mode      browserMode
+	input     textinput.Model
+	spin      spinner.Model
+	transfers bool
+}
+
+type browserMode int
+
+const (
+	modeBrowse browserMode = iota
+	modeConfirmDelete
+	modeRename
+	modeMkdir
+)
+
+// newSFTPBrowserModel opens an SFTP subsystem on client and lists the local
+// working directory alongside the remote home directory.
+func newSFTPBrowserModel(client *ssh.Client) (*sftpBrowserModel, error) {
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SFTP subsystem: %w", err)
+	}
+
+	localPath, err := os.Getwd()
+	if err != nil {
+		localPath = "."
+	}
+	remotePath := "."
+	if home, err := sftpClient.Getwd(); err == nil && home != "" {
+		remotePath = home
+	}
+
+	m := &sftpBrowserModel{
+		sftpClient: sftpClient,
+		local:      browserPane{path: localPath, selected: map[string]bool{}},
+		remote:     browserPane{path: remotePath, selected: map[string]bool{}},
+		input:      textinput.New(),
+		spin:       spinner.New(),
+	}
+	m.spin.Spinner = spinner.Dot
+
+	if err := m.reloadLocal(); err != nil {
+		return nil, err
+	}
+	if err := m.reloadRemote(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *sftpBrowserModel) Init() tea.Cmd { return nil }
+
+// activePane returns whichever pane currently has focus.
+func (m *sftpBrowserModel) activePane() *browserPane {
+	if m.focusRight {
+		return &m.remote
+	}
+	return &m.local
+}
+
+func (m *sftpBrowserModel) reloadLocal() error {
+	entries, err := os.ReadDir(m.local.path)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", m.local.path, err)
+	}
+
+	items := make([]list.Item, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, fileEntry{name: e.Name(), isDir: e.IsDir(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sortFileEntries(items)
+
+	m.local.list = newBrowserList(items, "Local: "+m.local.path)
+	return nil
+}
+
+func (m *sftpBrowserModel) reloadRemote() error {
+	infos, err := m.sftpClient.ReadDir(m.remote.path)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", m.remote.path, err)
+	}
+
+	items := make([]list.Item, 0, len(infos))
+	for _, info := range infos {
+		items = append(items, fileEntry{name: info.Name(), isDir: info.IsDir(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sortFileEntries(items)
+
+	m.remote.list = newBrowserList(items, "Remote: "+m.remote.path)
+	return nil
+}
+
+func sortFileEntries(items []list.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i].(fileEntry), items[j].(fileEntry)
+		if a.isDir != b.isDir {
+			return a.isDir
+		}
+		return a.name < b.name
+	})
+}
+
+func newBrowserList(items []list.Item, title string) list.Model {
+	l := list.New(items, list.NewDefaultDelegate(), 40, 20)
+	l.Title = title
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(true)
+	return l
+}
+
+// transferDoneMsg reports the outcome of a background file transfer.
+type transferDoneMsg struct {
+	err error
+}
+
+func (m *sftpBrowserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		paneWidth := m.width/2 - 4
+		m.local.list.SetSize(paneWidth, m.height-4)
+		m.remote.list.SetSize(paneWidth, m.height-4)
+		return m, nil
+
+	case transferDoneMsg:
+		m.transfers = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("transfer failed: %v", msg.err)
+		} else {
+			m.status = "transfer complete"
+		}
+		m.reloadLocal()
+		m.reloadRemote()
+		return m, nil
+
+	case spinner.TickMsg:
+		if m.transfers {
+			var cmd tea.Cmd
+			m.spin, cmd = m.spin.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m *sftpBrowserModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.mode {
+	case modeConfirmDelete:
+		return m.handleConfirmDeleteKey(msg)
+	case modeRename, modeMkdir:
+		return m.handleTextInputKey(msg)
+	}
+
+	switch msg.String() {
+	case "q", "esc", "ctrl+f":
+		return m, tea.Quit
+
+	case "tab":
+		m.focusRight = !m.focusRight
+		return m, nil
+
+	case "enter":
+		m.descend()
+		return m, nil
+
+	case "backspace":
+		m.ascend()
+		return m, nil
+
+	case " ":
+		m.toggleSelect()
+		return m, nil
+
+	case "t":
+		return m, m.startTransfer()
+
+	case "r":
+		return m.startRename()
+
+	case "m":
+		return m.startMkdir()
+
+	case "d":
+		m.mode = modeConfirmDelete
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	pane := m.activePane()
+	pane.list, cmd = pane.list.Update(msg)
+	return m, cmd
+}
+
+func (m *sftpBrowserModel) selectedEntry() (fileEntry, bool) {
+	pane := m.activePane()
+	item := pane.list.SelectedItem()
+	if item == nil {
+		return fileEntry{}, false
+	}
+	return item.(fileEntry), true
+}
+
+func (m *sftpBrowserModel) descend() {
+	entry, ok := m.selectedEntry()
+	if !ok || !entry.isDir {
+		return
+	}
+
+	pane := m.activePane()
+	if m.focusRight {
+		m.tryChangePath(pane, path.Join(pane.path, entry.name), m.reloadRemote)
+	} else {
+		m.tryChangePath(pane, filepath.Join(pane.path, entry.name), m.reloadLocal)
+	}
+}
+
+func (m *sftpBrowserModel) ascend() {
+	pane := m.activePane()
+	if m.focusRight {
+		m.tryChangePath(pane, path.Dir(pane.path), m.reloadRemote)
+	} else {
+		m.tryChangePath(pane, filepath.Dir(pane.path), m.reloadLocal)
+	}
+}
+
+// tryChangePath moves pane to newPath and reloads it with reload, but only
+// commits the path change if reload actually succeeds — otherwise the pane
+// would keep showing the old listing while pointed at a directory (bad
+// permissions, a broken symlink, ...) that fails every subsequent reload
+// too, with no way for the user to tell what went wrong.
+func (m *sftpBrowserModel) tryChangePath(pane *browserPane, newPath string, reload func() error) {
+	oldPath := pane.path
+	pane.path = newPath
+	if err := reload(); err != nil {
+		pane.path = oldPath
+		m.err = err
+		return
+	}
+	m.err = nil
+}
+
+func (m *sftpBrowserModel) toggleSelect() {
+	entry, ok := m.selectedEntry()
+	if !ok {
+		return
+	}
+	pane := m.activePane()
+	if pane.selected[entry.name] {
+		delete(pane.selected, entry.name)
+	} else {
+		pane.selected[entry.name] = true
+	}
+}
+
+// startTransfer copies the active pane's selection (or just the highlighted
+// entry) in the arrow direction: local pane focused means upload, remote
+// pane focused means download.
+func (m *sftpBrowserModel) startTransfer() tea.Cmd {
+	pane := m.activePane()
+	names := selectedNames(pane)
+	if len(names) == 0 {
+		return nil
+	}
+
+	m.transfers = true
+	uploading := !m.focusRight
+	localDir, remoteDir := m.local.path, m.remote.path
+
+	return tea.Batch(m.spin.Tick, func() tea.Msg {
+		for _, name := range names {
+			var err error
+			if uploading {
+				err = uploadFile(m.sftpClient, filepath.Join(localDir, name), path.Join(remoteDir, name))
+			} else {
+				err = downloadFile(m.sftpClient, path.Join(remoteDir, name), filepath.Join(localDir, name))
+			}
+			if err != nil {
+				return transferDoneMsg{err: err}
+			}
+		}
+		return transferDoneMsg{}
+	})
+}
+
+func selectedNames(pane *browserPane) []string {
+	if len(pane.selected) == 0 {
+		if entry, ok := pane.list.SelectedItem().(fileEntry); ok {
+			return []string{entry.name}
+		}
+		return nil
+	}
+	names := make([]string, 0, len(pane.selected))
+	for name := range pane.selected {
+		names = append(names, name)
+	}
+	return names
+}
+
+func uploadFile(client *sftp.Client, localPath, remotePath string) error {
+	local, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer local.Close()
+
+	remote, err := client.Create(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", remotePath, err)
+	}
+	defer remote.Close()
+
+	_, err = io.Copy(remote, local)
+	return err
+}
+
+func downloadFile(client *sftp.Client, remotePath, localPath string) error {
+	remote, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", remotePath, err)
+	}
+	defer remote.Close()
+
+	local, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", localPath, err)
+	}
+	defer local.Close()
+
+	_, err = io.Copy(local, remote)
+	return err
+}
+
+func (m *sftpBrowserModel) handleConfirmDeleteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y":
+		m.mode = modeBrowse
+		entry, ok := m.selectedEntry()
+		if !ok {
+			return m, nil
+		}
+		if m.focusRight {
+			m.err = m.sftpClient.Remove(path.Join(m.remote.path, entry.name))
+			m.reloadRemote()
+		} else {
+			m.err = os.Remove(filepath.Join(m.local.path, entry.name))
+			m.reloadLocal()
+		}
+	default:
+		m.mode = modeBrowse
+	}
+	return m, nil
+}
+
+func (m *sftpBrowserModel) startRename() (tea.Model, tea.Cmd) {
+	entry, ok := m.selectedEntry()
+	if !ok {
+		return m, nil
+	}
+	m.mode = modeRename
+	m.input.SetValue(entry.name)
+	m.input.Focus()
+	return m, nil
+}
+
+func (m *sftpBrowserModel) startMkdir() (tea.Model, tea.Cmd) {
+	m.mode = modeMkdir
+	m.input.SetValue("")
+	m.input.Focus()
+	return m, nil
+}
+
+func (m *sftpBrowserModel) handleTextInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		mode := m.mode
+		m.mode = modeBrowse
+		if value == "" {
+			return m, nil
+		}
+
+		if mode == modeRename {
+			m.rename(value)
+		} else {
+			m.mkdir(value)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *sftpBrowserModel) rename(newName string) {
+	entry, ok := m.selectedEntry()
+	if !ok {
+		return
+	}
+	if m.focusRight {
+		m.err = m.sftpClient.Rename(path.Join(m.remote.path, entry.name), path.Join(m.remote.path, newName))
+		m.reloadRemote()
+	} else {
+		m.err = os.Rename(filepath.Join(m.local.path, entry.name), filepath.Join(m.local.path, newName))
+		m.reloadLocal()
+	}
+}
+
+func (m *sftpBrowserModel) mkdir(name string) {
+	if m.focusRight {
+		m.err = m.sftpClient.Mkdir(path.Join(m.remote.path, name))
+		m.reloadRemote()
+	} else {
+		m.err = os.Mkdir(filepath.Join(m.local.path, name), 0o755)
+		m.reloadLocal()
+	}
+}
+
+func (m *sftpBrowserModel) View() string {
+	focusStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("170"))
+	blurStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+
+	localStyle, remoteStyle := blurStyle, blurStyle
+	if m.focusRight {
+		remoteStyle = focusStyle
+	} else {
+		localStyle = focusStyle
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		localStyle.Render(m.local.list.View()),
+		remoteStyle.Render(m.remote.list.View()),
+	)
+
+	status := m.status
+	switch m.mode {
+	case modeConfirmDelete:
+		status = "Delete selected entry? (y/n)"
+	case modeRename:
+		status = "Rename to: " + m.input.View()
+	case modeMkdir:
+		status = "New directory: " + m.input.View()
+	}
+	if m.transfers {
+		status = m.spin.View() + " transferring..."
+	}
+	if m.err != nil {
+		status = fmt.Sprintf("error: %v", m.err)
+	}
+
+	help := "tab: switch pane  enter: open  backspace: up  space: select  t: transfer  r: rename  d: delete  m: mkdir  q: quit"
+	return fmt.Sprintf("%s\n%s\n%s", panes, status, help)
+}
+
+// runSFTPBrowser runs the two-pane SFTP browser as its own Bubble Tea
+// program against client, and cleans up the SFTP subsystem once the user
+// quits. It does not change the local terminal's mode; the caller takes the
+// terminal out of raw mode before calling it.
+func runSFTPBrowser(client *ssh.Client) error {
+	model, err := newSFTPBrowserModel(client)
+	if err != nil {
+		return err
+	}
+	defer model.sftpClient.Close()
+
+	_, err = tea.NewProgram(model, tea.WithAltScreen()).Run()
+	return err
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/mikemackintosh/zzh/internal/configresolver"
+)
+
+// connectToSSHEmbedded connects to host using an in-process SSH client
+// instead of shelling out to the system ssh binary. It owns the byte stream
+// end to end, which is what lets session recording, SFTP and panel muxing
+// hook in further up the call stack.
+func connectToSSHEmbedded(host SSHHost, inZzhPanel bool) error {
+	authMethods, err := sshAuthMethods(host)
+	if err != nil {
+		return fmt.Errorf("failed to build SSH auth methods: %w", err)
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback()
+	if err != nil {
+		return fmt.Errorf("failed to set up host key verification: %w", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            host.user,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	}
+
+	addr := net.JoinHostPort(host.hostname, host.port)
+	client, jumpClients, err := dialThroughHops(host.proxyJump, addr, config)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", addr, err)
+	}
+	defer closeClientChain(client, jumpClients)
+
+	return runEmbeddedSession(client, host)
+}
+
+// closeClientChain closes client and every intermediate jump client that
+// was dialed through to reach it, in reverse dial order.
+func closeClientChain(client *ssh.Client, jumpClients []*ssh.Client) {
+	if client != nil {
+		client.Close()
+	}
+	for i := len(jumpClients) - 1; i >= 0; i-- {
+		jumpClients[i].Close()
+	}
+}
+
+// dialThroughHops dials addr, tunneling through each ProxyJump hop in turn
+// so the final connection is made as if originating from the last hop. It
+// returns the final client along with every intermediate jump client it
+// dialed through, in dial order, so the caller can close them all once the
+// session ends instead of leaking the jump connections.
+func dialThroughHops(hops []configresolver.SSHHop, addr string, finalConfig *ssh.ClientConfig) (*ssh.Client, []*ssh.Client, error) {
+	if len(hops) == 0 {
+		client, err := ssh.Dial("tcp", addr, finalConfig)
+		return client, nil, err
+	}
+
+	var jumpClients []*ssh.Client
+	var dialer func(network, address string) (net.Conn, error) = net.Dial
+
+	for _, hop := range hops {
+		hopHost := SSHHost{name: hop.HostName, hostname: hop.HostName, user: hop.User, port: hop.Port}
+		if hopHost.port == "" {
+			hopHost.port = "22"
+		}
+		if hopHost.user == "" {
+			hopHost.user = finalConfig.User
+		}
+
+		authMethods, err := sshAuthMethods(hopHost)
+		if err != nil {
+			closeClientChain(nil, jumpClients)
+			return nil, nil, fmt.Errorf("failed to build SSH auth methods for hop %s: %w", hop.HostName, err)
+		}
+		hostKeyCallback, err := sshHostKeyCallback()
+		if err != nil {
+			closeClientChain(nil, jumpClients)
+			return nil, nil, err
+		}
+
+		hopAddr := net.JoinHostPort(hopHost.hostname, hopHost.port)
+		conn, err := dialer("tcp", hopAddr)
+		if err != nil {
+			closeClientChain(nil, jumpClients)
+			return nil, nil, fmt.Errorf("failed to reach jump host %s: %w", hop.HostName, err)
+		}
+
+		clientConn, chans, reqs, err := ssh.NewClientConn(conn, hopAddr, &ssh.ClientConfig{
+			User:            hopHost.user,
+			Auth:            authMethods,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         15 * time.Second,
+		})
+		if err != nil {
+			closeClientChain(nil, jumpClients)
+			return nil, nil, fmt.Errorf("failed to establish SSH connection to jump host %s: %w", hop.HostName, err)
+		}
+
+		jumpClient := ssh.NewClient(clientConn, chans, reqs)
+		jumpClients = append(jumpClients, jumpClient)
+		dialer = jumpClient.Dial
+	}
+
+	conn, err := dialer("tcp", addr)
+	if err != nil {
+		closeClientChain(nil, jumpClients)
+		return nil, nil, fmt.Errorf("failed to reach %s through jump hosts: %w", addr, err)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, finalConfig)
+	if err != nil {
+		closeClientChain(nil, jumpClients)
+		return nil, nil, fmt.Errorf("failed to establish SSH connection to %s: %w", addr, err)
+	}
+
+	return ssh.NewClient(clientConn, chans, reqs), jumpClients, nil
+}
+
+// ctrlFReader watches a keystroke stream for ctrl+f (0x06) and invokes
+// onCtrlF when it sees one, stripping the byte so the remote shell never
+// receives it.
+type ctrlFReader struct {
+	r       io.Reader
+	onCtrlF func()
+}
+
+const ctrlF = 0x06
+
+func (c *ctrlFReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		if idx := bytes.IndexByte(p[:n], ctrlF); idx >= 0 {
+			copy(p[idx:n-1], p[idx+1:n])
+			n--
+			c.onCtrlF()
+		}
+	}
+	return n, err
+}
+
+// runEmbeddedSession opens a PTY-backed shell session on an established SSH
+// client connection and wires it up to the local terminal.
+func runEmbeddedSession(client *ssh.Client, host SSHHost) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	fd := int(os.Stdin.Fd())
+	width, height, err := getTerminalSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
+		return fmt.Errorf("failed to request PTY: %w", err)
+	}
+
+	var stdin io.Reader = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	var rec *Recorder
+	if *recordSession && *recordFormat == "cast" {
+		timestamp := time.Now().Format("20060102-150405")
+		rec, err = NewRecorder(fmt.Sprintf("ssh_session_%s_%s.cast", host.name, timestamp), width, height, *recordInput)
+		if err != nil {
+			return fmt.Errorf("failed to start recording: %w", err)
+		}
+		defer rec.Close()
+
+		stdin = io.TeeReader(stdin, inputRecordingWriter{rec})
+		session.Stdout = io.MultiWriter(os.Stdout, outputRecordingWriter{rec})
+		session.Stderr = io.MultiWriter(os.Stderr, outputRecordingWriter{rec})
+	}
+
+	state, err := setRawTerminal(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set raw terminal: %w", err)
+	}
+	defer restoreTerminal(fd, state)
+
+	// ctrl+f suspends the raw passthrough and swaps to the SFTP browser.
+	session.Stdin = &ctrlFReader{r: stdin, onCtrlF: func() {
+		restoreTerminal(fd, state)
+		if err := runSFTPBrowser(client); err != nil {
+			fmt.Printf("SFTP browser error: %v\n", err)
+		}
+		setRawTerminal(fd)
+	}}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	watchWindowResize(fd, stop, func() {
+		w, h, err := getTerminalSize(fd)
+		if err != nil {
+			return
+		}
+		session.WindowChange(h, w)
+		if rec != nil {
+			rec.Resize(w, h)
+		}
+	})
+
+	if err := session.Shell(); err != nil {
+		return fmt.Errorf("failed to start remote shell: %w", err)
+	}
+
+	if err := session.Wait(); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("remote shell exited with code %d", exitErr.ExitStatus())
+		}
+		return fmt.Errorf("remote shell session ended: %w", err)
+	}
+
+	fmt.Printf("Connection to %s closed.\n", host.name)
+	return nil
+}
+
+// sshAuthMethods builds the authentication chain for host: agent first,
+// then the configured identity file, then an interactive password prompt.
+func sshAuthMethods(host SSHHost) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	if authMethod, ok := sshAgentAuthMethod(); ok {
+		methods = append(methods, authMethod)
+	}
+
+	if host.identityFile != "" {
+		if authMethod, err := identityFileAuthMethod(host.identityFile); err == nil {
+			methods = append(methods, authMethod)
+		}
+	}
+
+	methods = append(methods, ssh.PasswordCallback(func() (string, error) {
+		return promptSecret(fmt.Sprintf("Password for %s@%s: ", host.user, host.hostname))
+	}))
+
+	return methods, nil
+}
+
+// sshAgentAuthMethod returns an auth method backed by the running
+// ssh-agent, if SSH_AUTH_SOCK is set and reachable.
+func sshAgentAuthMethod() (ssh.AuthMethod, bool) {
+	sockPath := os.Getenv("SSH_AUTH_SOCK")
+	if sockPath == "" {
+		return nil, false
+	}
+
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, false
+	}
+
+	agentClient := agent.NewClient(conn)
+	return ssh.PublicKeysCallback(agentClient.Signers), true
+}
+
+// identityFileAuthMethod loads a private key from path, prompting for a
+// passphrase if the key is encrypted.
+func identityFileAuthMethod(path string) (ssh.AuthMethod, error) {
+	keyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file %s: %w", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if err == nil {
+		return ssh.PublicKeys(signer), nil
+	}
+
+	var passphraseErr *ssh.PassphraseMissingError
+	block, _ := pem.Decode(keyBytes)
+	if block != nil && x509.IsEncryptedPEMBlock(block) || errors.As(err, &passphraseErr) {
+		passphrase, promptErr := promptSecret(fmt.Sprintf("Enter passphrase for key %s: ", path))
+		if promptErr != nil {
+			return nil, promptErr
+		}
+
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt identity file %s: %w", path, err)
+		}
+		return ssh.PublicKeys(signer), nil
+	}
+
+	return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+}
+
+// sshHostKeyCallback validates server host keys against ~/.ssh/known_hosts,
+// prompting to trust-on-first-use any host it has not seen before.
+func sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+
+	knownHostsPath := filepath.Join(usr, ".ssh", "known_hosts")
+	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(knownHostsPath), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create ~/.ssh: %w", err)
+		}
+		if f, err := os.OpenFile(knownHostsPath, os.O_CREATE|os.O_WRONLY, 0o600); err == nil {
+			f.Close()
+		}
+	}
+
+	baseCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := baseCallback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			// Either an unrelated error, or the host is known under a
+			// different key - refuse rather than silently trusting it.
+			return err
+		}
+
+		if !confirmUnknownHostKey(hostname, key) {
+			return fmt.Errorf("host key verification refused for %s", hostname)
+		}
+
+		return appendKnownHost(knownHostsPath, hostname, key)
+	}, nil
+}
+
+// confirmUnknownHostKey prompts the user to accept a host key seen for the
+// first time, in the style of OpenSSH's TOFU prompt.
+func confirmUnknownHostKey(hostname string, key ssh.PublicKey) bool {
+	fmt.Printf("The authenticity of host '%s' can't be established.\n", hostname)
+	fmt.Printf("%s key fingerprint is %s.\n", key.Type(), ssh.FingerprintSHA256(key))
+	fmt.Print("Are you sure you want to continue connecting (yes/no)? ")
+
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(answer)) == "yes"
+}
+
+// appendKnownHost records hostname's key in the known_hosts file at path.
+func appendKnownHost(path, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{hostname}, key)
+	if _, err := io.WriteString(f, line+"\n"); err != nil {
+		return fmt.Errorf("failed to update known_hosts: %w", err)
+	}
+	return nil
+}
+
+// promptSecret reads a line from stdin without echoing it back, for
+// passwords and key passphrases.
+func promptSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	secret, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(secret), nil
+}
@@ -4,6 +4,8 @@
 package main
 
 import (
+	"os"
+	"os/signal"
 	"syscall"
 	"unsafe"
 
@@ -36,3 +38,22 @@ func getTerminalSize(fd int) (width, height int, err error) {
 	}
 	return int(dimensions[1]), int(dimensions[0]), nil
 }
+
+// watchWindowResize invokes onResize every time the controlling terminal's
+// size changes, until stop is closed.
+func watchWindowResize(fd int, stop <-chan struct{}, onResize func()) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-sigChan:
+				onResize()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
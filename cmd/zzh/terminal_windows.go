@@ -4,6 +4,8 @@
 package main
 
 import (
+	"time"
+
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -28,3 +30,30 @@ func getTerminalSize(fd int) (int, int, error) {
 	}
 	return width, height, nil
 }
+
+// watchWindowResize invokes onResize every time the controlling terminal's
+// size changes, until stop is closed. Windows has no SIGWINCH, so the size
+// is polled instead.
+func watchWindowResize(fd int, stop <-chan struct{}, onResize func()) {
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+
+		lastWidth, lastHeight, _ := getTerminalSize(fd)
+		for {
+			select {
+			case <-ticker.C:
+				width, height, err := getTerminalSize(fd)
+				if err != nil {
+					continue
+				}
+				if width != lastWidth || height != lastHeight {
+					lastWidth, lastHeight = width, height
+					onResize()
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+// Package configresolver parses OpenSSH client config files with the
+// semantics real ssh_config(5) files rely on: Include directives, Match
+// blocks, wildcard Host patterns, and ProxyJump/ProxyCommand chains. It
+// replaces a naive single-file, no-wildcards loader.
+package configresolver
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// Host is the fully resolved set of options for one concrete host alias,
+// after the Include/Match/wildcard cascade has been applied.
+type Host struct {
+	Alias        string
+	HostName     string
+	User         string
+	Port         string
+	IdentityFile string
+	ProxyJump    []SSHHop
+	ProxyCommand string
+}
+
+// SSHHop is one hop in a ProxyJump chain, in the order they must be dialed
+// (the first hop is reached directly, the last hop reaches the final host).
+type SSHHop struct {
+	User     string
+	HostName string
+	Port     string
+}
+
+// Resolver holds every Host/Match block parsed out of a config file and its
+// Include'd files, in file order, so it can cascade options the way OpenSSH
+// does: the first value seen for a parameter wins.
+type Resolver struct {
+	blocks      []configBlock
+	currentUser string
+}
+
+// NewResolver loads path (recursively following Include directives relative
+// to ~/.ssh/ and /etc/ssh/) and prepares it for querying.
+func NewResolver(path string) (*Resolver, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	loader := &includeLoader{
+		homeSSHDir: filepath.Join(usr.HomeDir, ".ssh"),
+		etcSSHDir:  "/etc/ssh",
+		seen:       map[string]bool{},
+	}
+
+	blocks, err := loader.load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{blocks: blocks, currentUser: usr.Username}, nil
+}
+
+// Aliases returns every concrete (non-wildcard) host alias declared across
+// the config and its includes, in first-seen order.
+func (r *Resolver) Aliases() []string {
+	var aliases []string
+	seen := map[string]bool{}
+
+	for _, b := range r.blocks {
+		if b.kind != blockHost {
+			continue
+		}
+		for _, pattern := range b.patterns {
+			if pattern.negated || isWildcard(pattern.text) {
+				continue
+			}
+			if seen[pattern.text] {
+				continue
+			}
+			seen[pattern.text] = true
+			aliases = append(aliases, pattern.text)
+		}
+	}
+
+	return aliases
+}
+
+// Resolve cascades every Host/Match block against alias and returns its
+// effective options, applying Match-final blocks once the normal cascade
+// has settled.
+func (r *Resolver) Resolve(alias string) (Host, error) {
+	host := Host{Alias: alias, Port: "22"}
+	resolved := map[string]string{}
+
+	var finalBlocks []configBlock
+
+	for _, b := range r.blocks {
+		if b.final {
+			finalBlocks = append(finalBlocks, b)
+			continue
+		}
+		if !r.blockApplies(b, alias, resolved) {
+			continue
+		}
+		applyBlock(b, resolved)
+	}
+
+	for _, b := range finalBlocks {
+		if !r.blockApplies(b, alias, resolved) {
+			continue
+		}
+		applyBlock(b, resolved)
+	}
+
+	host.HostName = firstOr(resolved["hostname"], alias)
+	host.User = firstOr(resolved["user"], r.currentUser)
+	host.Port = firstOr(resolved["port"], "22")
+	host.ProxyCommand = resolved["proxycommand"]
+
+	if identity, ok := resolved["identityfile"]; ok {
+		host.IdentityFile = expandHome(identity)
+	}
+
+	if jump, ok := resolved["proxyjump"]; ok && jump != "" && jump != "none" {
+		hops, err := parseProxyJump(jump, r)
+		if err != nil {
+			return host, fmt.Errorf("failed to resolve ProxyJump for %s: %w", alias, err)
+		}
+		host.ProxyJump = hops
+	}
+
+	return host, nil
+}
+
+// blockApplies decides whether block b's pattern (Host) or condition
+// (Match) selects alias, given the options resolved so far.
+func (r *Resolver) blockApplies(b configBlock, alias string, resolvedSoFar map[string]string) bool {
+	switch b.kind {
+	case blockHost:
+		return patternsMatch(b.patterns, alias)
+	case blockMatch:
+		candidateUser := firstOr(resolvedSoFar["user"], r.currentUser)
+		return evalMatchExpr(b.matchExpr, alias, candidateUser)
+	default:
+		return false
+	}
+}
+
+// applyBlock merges b's directives into resolved, keeping the first value
+// seen for each key (OpenSSH's "first obtained value wins" rule).
+func applyBlock(b configBlock, resolved map[string]string) {
+	for key, value := range b.options {
+		if _, exists := resolved[key]; exists {
+			continue
+		}
+		resolved[key] = value
+	}
+}
+
+func firstOr(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+func expandHome(path string) string {
+	if path == "" || path[0] != '~' {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,171 @@
+package configresolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTokenizeMatchExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{
+			name: "simple criteria",
+			expr: "host web-* user deploy",
+			want: []string{"host", "web-*", "user", "deploy"},
+		},
+		{
+			name: "quoted exec command keeps spaces",
+			expr: `exec "test -f /etc/is-staging"`,
+			want: []string{"exec", "test -f /etc/is-staging"},
+		},
+		{
+			name: "quoted exec mixed with other criteria",
+			expr: `host * exec "echo %h && true"`,
+			want: []string{"host", "*", "exec", "echo %h && true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenizeMatchExpr(tt.expr)
+			if len(got) != len(tt.want) {
+				t.Fatalf("tokenizeMatchExpr(%q) = %#v, want %#v", tt.expr, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("tokenizeMatchExpr(%q)[%d] = %q, want %q", tt.expr, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEvalMatchExpr_QuotedExecSurvives(t *testing.T) {
+	// A multi-word exec command wrapped in quotes must reach the shell
+	// intact; before the tokenizer fix this was truncated at the first
+	// space inside the quotes.
+	if !evalMatchExpr(`exec "test -n 'hello'"`, "anyhost", "anyuser") {
+		t.Fatal("expected quoted exec command to evaluate true")
+	}
+	if evalMatchExpr(`exec "test -z 'hello'"`, "anyhost", "anyuser") {
+		t.Fatal("expected quoted exec command to evaluate false")
+	}
+}
+
+func TestEvalMatchExpr_HostAndUser(t *testing.T) {
+	if !evalMatchExpr("host web-*,db-* user deploy", "web-1", "deploy") {
+		t.Fatal("expected host+user match to succeed")
+	}
+	if evalMatchExpr("host web-*,db-* user deploy", "web-1", "root") {
+		t.Fatal("expected user mismatch to fail")
+	}
+	if evalMatchExpr("host web-*,db-* user deploy", "cache-1", "deploy") {
+		t.Fatal("expected host mismatch to fail")
+	}
+}
+
+// writeConfig writes contents to name inside dir and returns the full path.
+func writeConfig(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write fixture %s: %v", path, err)
+	}
+	return path
+}
+
+func TestIncludeLoader_NestedInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "inner.conf", "Host inner\n  HostName inner.example.com\n")
+	writeConfig(t, dir, "extra.conf", "Include inner.conf\n\nHost extra\n  HostName extra.example.com\n  User extrauser\n")
+	root := writeConfig(t, dir, "config", "Include extra.conf\n\nHost main\n  HostName main.example.com\n")
+
+	loader := &includeLoader{homeSSHDir: dir, etcSSHDir: dir, seen: map[string]bool{}}
+	blocks, err := loader.load(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks from nested Include expansion, got %d", len(blocks))
+	}
+	if blocks[0].options["hostname"] != "inner.example.com" {
+		t.Fatalf("nested included block not merged in file order: %#v", blocks[0])
+	}
+	if blocks[1].options["hostname"] != "extra.example.com" {
+		t.Fatalf("included block not merged in file order: %#v", blocks[1])
+	}
+}
+
+func TestResolver_MatchExecGatesOptions(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `Host staging-*
+  HostName %h.staging.internal
+
+Match exec "true"
+  User staging-deploy
+
+Match exec "false"
+  User never-applied
+`
+	root := writeConfig(t, dir, "config", cfg)
+
+	loader := &includeLoader{homeSSHDir: dir, etcSSHDir: dir, seen: map[string]bool{}}
+	blocks, err := loader.load(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	r := &Resolver{blocks: blocks, currentUser: "localuser"}
+	host, err := r.Resolve("staging-1")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if host.User != "staging-deploy" {
+		t.Fatalf("expected Match exec \"true\" block to apply User, got %q", host.User)
+	}
+}
+
+func TestParseProxyJump_MultiHopChain(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `Host bastion1
+  HostName bastion1.example.com
+  User jump1
+
+Host bastion2
+  HostName bastion2.example.com
+  User jump2
+
+Host target
+  HostName target.internal
+  ProxyJump bastion1,bastion2,literal@10.0.0.5:2222
+`
+	root := writeConfig(t, dir, "config", cfg)
+
+	loader := &includeLoader{homeSSHDir: dir, etcSSHDir: dir, seen: map[string]bool{}}
+	blocks, err := loader.load(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	r := &Resolver{blocks: blocks, currentUser: "localuser"}
+	host, err := r.Resolve("target")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if len(host.ProxyJump) != 3 {
+		t.Fatalf("expected 3 ProxyJump hops, got %d: %#v", len(host.ProxyJump), host.ProxyJump)
+	}
+	if host.ProxyJump[0].HostName != "bastion1.example.com" || host.ProxyJump[0].User != "jump1" {
+		t.Fatalf("unexpected first hop: %#v", host.ProxyJump[0])
+	}
+	if host.ProxyJump[1].HostName != "bastion2.example.com" || host.ProxyJump[1].User != "jump2" {
+		t.Fatalf("unexpected second hop: %#v", host.ProxyJump[1])
+	}
+	if host.ProxyJump[2].HostName != "10.0.0.5" || host.ProxyJump[2].Port != "2222" || host.ProxyJump[2].User != "literal" {
+		t.Fatalf("unexpected literal hop: %#v", host.ProxyJump[2])
+	}
+}
@@ -0,0 +1,222 @@
+package configresolver
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// blockKind distinguishes a Host block from a Match block.
+type blockKind int
+
+const (
+	blockHost blockKind = iota
+	blockMatch
+)
+
+// hostPattern is a single space-separated token of a Host line, e.g. the
+// "!*.example.com" in "Host foo !*.example.com".
+type hostPattern struct {
+	text    string
+	negated bool
+}
+
+// configBlock is one Host or Match stanza together with the directives it
+// sets, collected from the file (and its Include'd files) in source order.
+type configBlock struct {
+	kind      blockKind
+	patterns  []hostPattern // set for blockHost
+	matchExpr string        // set for blockMatch
+	final     bool          // "Match final ..."
+	options   map[string]string
+}
+
+// includeLoader walks a root config file and its Include directives,
+// flattening everything into a single ordered list of blocks.
+type includeLoader struct {
+	homeSSHDir string
+	etcSSHDir  string
+	seen       map[string]bool
+}
+
+// load reads path and recursively expands any Include directives it finds,
+// relative to ~/.ssh/ and /etc/ssh/ when the included pattern is not itself
+// absolute.
+func (l *includeLoader) load(path string) ([]configBlock, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path %s: %w", path, err)
+	}
+	if l.seen[absPath] {
+		return nil, nil // already included; avoid cycles
+	}
+	l.seen[absPath] = true
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH config file %s: %w", absPath, err)
+	}
+	defer f.Close()
+
+	var blocks []configBlock
+	var current *configBlock
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := splitDirective(line)
+		switch strings.ToLower(key) {
+		case "include":
+			if current != nil {
+				// Emit the enclosing block before the included ones so the
+				// list stays in source order; directives after the Include
+				// continue under the same Host/Match condition.
+				blocks = append(blocks, *current)
+				next := *current
+				next.options = map[string]string{}
+				current = &next
+			}
+			included, err := l.expandInclude(value)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, included...)
+
+		case "host":
+			if current != nil {
+				blocks = append(blocks, *current)
+			}
+			current = &configBlock{
+				kind:     blockHost,
+				patterns: parsePatternList(value),
+				options:  map[string]string{},
+			}
+
+		case "match":
+			if current != nil {
+				blocks = append(blocks, *current)
+			}
+			expr, final := extractFinal(value)
+			current = &configBlock{
+				kind:      blockMatch,
+				matchExpr: expr,
+				final:     final,
+				options:   map[string]string{},
+			}
+
+		default:
+			if current == nil {
+				// A directive before any Host/Match applies to everything;
+				// model it as an unconditional Host * block.
+				current = &configBlock{
+					kind:     blockHost,
+					patterns: []hostPattern{{text: "*"}},
+					options:  map[string]string{},
+				}
+			}
+			if _, exists := current.options[strings.ToLower(key)]; !exists {
+				current.options[strings.ToLower(key)] = value
+			}
+		}
+	}
+	if current != nil {
+		blocks = append(blocks, *current)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read SSH config file %s: %w", absPath, err)
+	}
+
+	return blocks, nil
+}
+
+// expandInclude resolves an Include directive's (possibly globbed, possibly
+// multi-token) value into the blocks of every file it matches.
+func (l *includeLoader) expandInclude(value string) ([]configBlock, error) {
+	var blocks []configBlock
+
+	for _, pattern := range strings.Fields(value) {
+		candidates := []string{pattern}
+		if !filepath.IsAbs(pattern) {
+			candidates = []string{
+				filepath.Join(l.homeSSHDir, pattern),
+				filepath.Join(l.etcSSHDir, pattern),
+			}
+		}
+
+		var matched []string
+		for _, candidate := range candidates {
+			paths, err := filepath.Glob(candidate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Include pattern %s: %w", pattern, err)
+			}
+			matched = append(matched, paths...)
+			if len(paths) > 0 {
+				break // ~/.ssh/ takes precedence over /etc/ssh/ for the same pattern
+			}
+		}
+
+		for _, match := range matched {
+			included, err := l.load(match)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, included...)
+		}
+	}
+
+	return blocks, nil
+}
+
+// splitDirective splits a config line into its keyword and the remainder of
+// the line. It accepts both "Key value" and "Key=value" forms. The value is
+// only unquoted here if it is wrapped in a single pair of quotes end to
+// end (e.g. IdentityFile "~/.ssh/id_ed25519") — values with quotes around
+// just one of several tokens (e.g. Match exec "...") are left alone so
+// their own parser can tokenize them correctly.
+func splitDirective(line string) (key, value string) {
+	line = strings.Replace(line, "=", " ", 1)
+	fields := strings.SplitN(line, " ", 2)
+	key = strings.TrimSpace(fields[0])
+	if len(fields) == 2 {
+		value = strings.TrimSpace(fields[1])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' &&
+			!strings.Contains(value[1:len(value)-1], `"`) {
+			value = value[1 : len(value)-1]
+		}
+	}
+	return key, value
+}
+
+// parsePatternList splits a Host line's value into its space-separated
+// patterns, tracking negation ("!pattern").
+func parsePatternList(value string) []hostPattern {
+	var patterns []hostPattern
+	for _, tok := range strings.Fields(value) {
+		if strings.HasPrefix(tok, "!") {
+			patterns = append(patterns, hostPattern{text: tok[1:], negated: true})
+		} else {
+			patterns = append(patterns, hostPattern{text: tok})
+		}
+	}
+	return patterns
+}
+
+// extractFinal pulls the "final" keyword out of a Match expression, which
+// can appear anywhere in the criteria list.
+func extractFinal(expr string) (remaining string, final bool) {
+	var kept []string
+	for _, tok := range strings.Fields(expr) {
+		if strings.EqualFold(tok, "final") {
+			final = true
+			continue
+		}
+		kept = append(kept, tok)
+	}
+	return strings.Join(kept, " "), final
+}
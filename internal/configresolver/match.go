@@ -0,0 +1,152 @@
+package configresolver
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// isWildcard reports whether pattern contains any glob metacharacters.
+func isWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// patternsMatch reports whether alias matches the given Host patterns,
+// honoring negation: if any negated pattern matches, the whole list fails,
+// regardless of what else matches.
+func patternsMatch(patterns []hostPattern, alias string) bool {
+	matched := false
+	for _, p := range patterns {
+		ok, _ := filepath.Match(p.text, alias)
+		if p.negated {
+			if ok {
+				return false
+			}
+			continue
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// evalMatchExpr evaluates a Match block's (already final-stripped) criteria
+// string against a candidate alias/user, e.g. "host web-* user deploy" or
+// "exec \"test -f /etc/is-staging\"".
+func evalMatchExpr(expr, alias, user string) bool {
+	if expr == "" || strings.EqualFold(expr, "all") {
+		return true
+	}
+
+	tokens := tokenizeMatchExpr(expr)
+	for i := 0; i < len(tokens); i++ {
+		keyword := strings.ToLower(tokens[i])
+		negate := false
+		if strings.HasPrefix(keyword, "!") {
+			negate = true
+			keyword = keyword[1:]
+		}
+
+		switch keyword {
+		case "host", "originalhost":
+			if i+1 >= len(tokens) {
+				return false
+			}
+			i++
+			ok := patternListMatch(tokens[i], alias)
+			if ok == negate {
+				return false
+			}
+
+		case "user", "localuser":
+			if i+1 >= len(tokens) {
+				return false
+			}
+			i++
+			ok := patternListMatch(tokens[i], user)
+			if ok == negate {
+				return false
+			}
+
+		case "exec":
+			if i+1 >= len(tokens) {
+				return false
+			}
+			i++
+			command := strings.Join(tokens[i:], " ")
+			command = strings.ReplaceAll(command, "%h", alias)
+			ok := execConditionMatches(command)
+			if ok == negate {
+				return false
+			}
+			i = len(tokens)
+
+		default:
+			// Unknown/unsupported criteria (canonical, tagged, ...) are
+			// treated as non-matching rather than silently ignored.
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenizeMatchExpr splits a Match criteria string into tokens the same way
+// ssh_config(5) does: whitespace-separated, except a double-quoted run
+// (used by "exec \"command with spaces\"") is kept as a single token with
+// its quotes stripped, so the exec command isn't shredded on every space.
+func tokenizeMatchExpr(expr string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes := false
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ' ' && !inQuotes:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// patternListMatch matches candidate against a comma-separated pattern
+// list, where a leading "!" negates that one entry.
+func patternListMatch(list, candidate string) bool {
+	matched := false
+	for _, pattern := range strings.Split(list, ",") {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		ok, _ := filepath.Match(pattern, candidate)
+		if negated {
+			if ok {
+				return false
+			}
+			continue
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// execConditionMatches runs command through the shell and reports whether
+// it exited successfully, per Match exec semantics.
+func execConditionMatches(command string) bool {
+	cmd := exec.Command("sh", "-c", command)
+	return cmd.Run() == nil
+}
@@ -0,0 +1,58 @@
+package configresolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseProxyJump parses a "ProxyJump a,b,c" directive value into an ordered
+// chain of hops. Each entry is either a literal "[user@]host[:port]" spec or
+// the alias of another Host block in resolver, which is resolved the same
+// way a top-level host would be.
+func parseProxyJump(value string, resolver *Resolver) ([]SSHHop, error) {
+	var hops []SSHHop
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if looksLikeLiteralHop(entry) {
+			hops = append(hops, parseLiteralHop(entry))
+			continue
+		}
+
+		hop, err := resolver.Resolve(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ProxyJump hop %s: %w", entry, err)
+		}
+		hops = append(hops, SSHHop{User: hop.User, HostName: hop.HostName, Port: hop.Port})
+	}
+
+	return hops, nil
+}
+
+// looksLikeLiteralHop reports whether entry is an inline "user@host:port"
+// spec rather than a reference to another Host alias.
+func looksLikeLiteralHop(entry string) bool {
+	return strings.Contains(entry, "@") || strings.Contains(entry, ":")
+}
+
+func parseLiteralHop(entry string) SSHHop {
+	hop := SSHHop{Port: "22"}
+
+	if at := strings.Index(entry, "@"); at >= 0 {
+		hop.User = entry[:at]
+		entry = entry[at+1:]
+	}
+
+	if colon := strings.LastIndex(entry, ":"); colon >= 0 {
+		hop.HostName = entry[:colon]
+		hop.Port = entry[colon+1:]
+	} else {
+		hop.HostName = entry
+	}
+
+	return hop
+}